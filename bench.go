@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// -----------------------------
+// --bench mode
+// -----------------------------
+
+// benchResult is one --bench iteration's timing/throughput/allocation
+// report, emitted as a single JSON line so runs can be diffed in CI.
+type benchResult struct {
+	Iteration            int     `json:"iteration"`
+	Size                 int     `json:"size"`
+	Chunks               int     `json:"chunks"`
+	Workers              int     `json:"workers"`
+	GenerateMS           float64 `json:"generate_ms"`
+	SplitMS              float64 `json:"split_ms"`
+	SortMS               float64 `json:"sort_ms"`
+	MergeMS              float64 `json:"merge_ms"`
+	TotalMS              float64 `json:"total_ms"`
+	ThroughputIntsPerSec float64 `json:"throughput_ints_per_sec"`
+	AllocBytes           uint64  `json:"alloc_bytes"`
+	Mallocs              uint64  `json:"mallocs"`
+}
+
+// runBench drives the split->sort->merge pipeline for n iterations of size
+// random integers each (seeded by seed, so runs are reproducible), or, if
+// readFile is set, instead runs a random seek+read latency probe against an
+// existing sorted output file. Either way results are reported as JSON
+// lines on stdout.
+func runBench(n, size int, seed int64, chunksOverride, workers int, readFile string, probes, maxBlock int) error {
+	if readFile != "" {
+		return runReadPatternBench(readFile, probes, maxBlock, seed)
+	}
+	return runSortBench(n, size, seed, chunksOverride, workers)
+}
+
+func runSortBench(n, size int, seed int64, chunksOverride, workers int) error {
+	if n < 1 {
+		return fmt.Errorf("bench-n must be >= 1")
+	}
+	if size < 1 {
+		return fmt.Errorf("bench-size must be >= 1")
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	encoder := json.NewEncoder(os.Stdout)
+
+	for iter := 0; iter < n; iter++ {
+		var memBefore, memAfter runtime.MemStats
+		runtime.ReadMemStats(&memBefore)
+
+		start := time.Now()
+
+		genStart := time.Now()
+		numbers := make([]int, size)
+		for i := range numbers {
+			numbers[i] = rng.Int()
+		}
+		generateDur := time.Since(genStart)
+
+		splitStart := time.Now()
+		var chunks [][]int
+		if chunksOverride > 0 {
+			chunks = splitIntoChunksN(numbers, chunksOverride)
+		} else {
+			chunks = splitIntoChunks(numbers)
+		}
+		splitDur := time.Since(splitStart)
+
+		sortStart := time.Now()
+		sortedChunks := sortChunksConcurrently(chunks, workers)
+		sortDur := time.Since(sortStart)
+
+		mergeStart := time.Now()
+		mergeSortedChunks(sortedChunks)
+		mergeDur := time.Since(mergeStart)
+
+		totalDur := time.Since(start)
+
+		runtime.ReadMemStats(&memAfter)
+
+		result := benchResult{
+			Iteration:            iter,
+			Size:                 size,
+			Chunks:               len(chunks),
+			Workers:              workers,
+			GenerateMS:           generateDur.Seconds() * 1000,
+			SplitMS:              splitDur.Seconds() * 1000,
+			SortMS:               sortDur.Seconds() * 1000,
+			MergeMS:              mergeDur.Seconds() * 1000,
+			TotalMS:              totalDur.Seconds() * 1000,
+			ThroughputIntsPerSec: float64(size) / totalDur.Seconds(),
+			AllocBytes:           memAfter.TotalAlloc - memBefore.TotalAlloc,
+			Mallocs:              memAfter.Mallocs - memBefore.Mallocs,
+		}
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitIntoChunksN is splitIntoChunks with an explicit chunk count instead
+// of the sqrt(n) heuristic, for benchmarking different chunk counts.
+func splitIntoChunksN(numbers []int, numChunks int) [][]int {
+	n := len(numbers)
+	if numChunks < 1 {
+		numChunks = 1
+	}
+	if numChunks > n {
+		numChunks = n
+	}
+
+	chunks := make([][]int, numChunks)
+	baseSize := n / numChunks
+	remainder := n % numChunks
+
+	index := 0
+	for i := 0; i < numChunks; i++ {
+		size := baseSize
+		if i < remainder {
+			size++
+		}
+		chunks[i] = numbers[index : index+size]
+		index += size
+	}
+	return chunks
+}
+
+// -----------------------------
+// --bench read-pattern mode
+// -----------------------------
+
+// readProbeResult reports latency statistics for a read-pattern probe run.
+type readProbeResult struct {
+	File          string  `json:"file"`
+	Probes        int     `json:"probes"`
+	MaxBlock      int     `json:"max_block"`
+	MeanLatencyUS float64 `json:"mean_latency_us"`
+	P95LatencyUS  float64 `json:"p95_latency_us"`
+}
+
+// runReadPatternBench performs probes random Seek+ReadFull reads of random
+// block sizes (up to maxBlock bytes) against path and reports mean/95th
+// percentile latency, to compare the read-side impact of different chunk
+// counts, worker counts, and segment sizes.
+func runReadPatternBench(path string, probes, maxBlock int, seed int64) error {
+	if probes < 1 {
+		return fmt.Errorf("bench-probes must be >= 1")
+	}
+	if maxBlock < 1 {
+		return fmt.Errorf("bench-max-block must be >= 1")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	fileSize := info.Size()
+	if fileSize == 0 {
+		return fmt.Errorf("%s is empty", path)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	latencies := make([]float64, 0, probes)
+
+	for i := 0; i < probes; i++ {
+		blockSize := int64(1 + rng.Intn(maxBlock))
+		if blockSize > fileSize {
+			blockSize = fileSize
+		}
+		offset := rng.Int63n(fileSize - blockSize + 1)
+
+		buf := make([]byte, blockSize)
+		start := time.Now()
+		_, err := file.ReadAt(buf, offset)
+		latency := time.Since(start)
+		if err != nil {
+			return fmt.Errorf("probe %d at offset %d: %w", i, offset, err)
+		}
+
+		latencies = append(latencies, latency.Seconds()*1e6)
+	}
+
+	result := readProbeResult{
+		File:          path,
+		Probes:        probes,
+		MaxBlock:      maxBlock,
+		MeanLatencyUS: mean(latencies),
+		P95LatencyUS:  percentile(latencies, 0.95),
+	}
+	return json.NewEncoder(os.Stdout).Encode(result)
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureStdout redirects os.Stdout for the duration of fn and returns
+// everything written to it.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+
+	fn()
+
+	os.Stdout = orig
+	w.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestRunSortBenchValidation(t *testing.T) {
+	if err := runSortBench(0, 100, 1, 0, 1); err == nil {
+		t.Fatal("expected an error for bench-n < 1")
+	}
+	if err := runSortBench(1, 0, 1, 0, 1); err == nil {
+		t.Fatal("expected an error for bench-size < 1")
+	}
+}
+
+func TestRunSortBenchEmitsOneJSONLinePerIteration(t *testing.T) {
+	var out string
+	out = captureStdout(t, func() {
+		if err := runSortBench(3, 50, 1, 0, 2); err != nil {
+			t.Fatalf("runSortBench: %v", err)
+		}
+	})
+
+	lines := strings.Split(strings.TrimRight(out, "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d JSON lines, want 3: %q", len(lines), out)
+	}
+
+	for i, line := range lines {
+		var result benchResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			t.Fatalf("line %d: invalid JSON %q: %v", i, line, err)
+		}
+		if result.Iteration != i {
+			t.Errorf("line %d: Iteration = %d, want %d", i, result.Iteration, i)
+		}
+		if result.Size != 50 {
+			t.Errorf("line %d: Size = %d, want 50", i, result.Size)
+		}
+		if result.Workers != 2 {
+			t.Errorf("line %d: Workers = %d, want 2", i, result.Workers)
+		}
+		if result.Chunks <= 0 {
+			t.Errorf("line %d: Chunks = %d, want > 0", i, result.Chunks)
+		}
+	}
+}
+
+func TestRunReadPatternBenchValidation(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "bench-read-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("1\n2\n3\n")
+	f.Close()
+
+	if err := runReadPatternBench(f.Name(), 0, 16, 1); err == nil {
+		t.Fatal("expected an error for bench-probes < 1")
+	}
+	if err := runReadPatternBench(f.Name(), 1, 0, 1); err == nil {
+		t.Fatal("expected an error for bench-max-block < 1")
+	}
+}
+
+func TestRunReadPatternBenchRejectsEmptyFile(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "bench-empty-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err := runReadPatternBench(f.Name(), 1, 16, 1); err == nil {
+		t.Fatal("expected an error for an empty --bench-read-file")
+	}
+}
+
+func TestRunReadPatternBenchEmitsJSONSummary(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/sorted.txt"
+
+	w, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bufw := bufio.NewWriter(w)
+	for i := 0; i < 1000; i++ {
+		bufw.WriteString("0123456789\n")
+	}
+	if err := bufw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	out := captureStdout(t, func() {
+		if err := runReadPatternBench(path, 10, 32, 1); err != nil {
+			t.Fatalf("runReadPatternBench: %v", err)
+		}
+	})
+
+	var result readProbeResult
+	if err := json.Unmarshal([]byte(strings.TrimSpace(out)), &result); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if result.File != path {
+		t.Errorf("File = %q, want %q", result.File, path)
+	}
+	if result.Probes != 10 {
+		t.Errorf("Probes = %d, want 10", result.Probes)
+	}
+	if result.MaxBlock != 32 {
+		t.Errorf("MaxBlock = %d, want 32", result.MaxBlock)
+	}
+	if result.MeanLatencyUS < 0 {
+		t.Errorf("MeanLatencyUS = %v, want >= 0", result.MeanLatencyUS)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// -----------------------------
+// -x mode: external k-way merge sort
+// -----------------------------
+//
+// runExternalSort streams path in fixed-size batches of chunkLines records
+// (each line's sort key extracted via keyFn), sorts each batch
+// concurrently, and spills it to a temp file in os.TempDir(). Once every
+// batch has been flushed, the temp files are merged back into a single
+// sorted output using a container/heap-based k-way merge, so the full
+// dataset never needs to fit in memory at once. If topK > 0, no chunks are
+// spilled at all: each batch contributes its local top topK records to a
+// shared bounded min-heap, and only the topK largest records overall are
+// written out.
+func runExternalSort(path string, chunkLines, workers int, keyFn KeyFunc, topK int) error {
+	if chunkLines < 1 {
+		return errors.New("chunk-lines must be >= 1")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	batches := make(chan []Record)
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(batches)
+
+		scanner := bufio.NewScanner(file)
+		var batch []Record
+
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			rec, err := keyFn(line)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			batch = append(batch, rec)
+			if len(batch) == chunkLines {
+				batches <- batch
+				batch = nil
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			readErr <- err
+			return
+		}
+		if len(batch) > 0 {
+			batches <- batch
+		}
+	}()
+
+	var (
+		summary     string
+		pipelineErr error
+	)
+	if topK > 0 {
+		summary, pipelineErr = runExternalTopK(batches, workers, topK, path)
+	} else {
+		summary, pipelineErr = runExternalFullSort(batches, workers, path)
+	}
+
+	// The reader goroutine may have abandoned batches (and so finished the
+	// pipeline above early) because a line failed to parse; that error
+	// takes priority over whatever partial result the pipeline produced.
+	select {
+	case readErr := <-readErr:
+		return readErr
+	default:
+	}
+	if pipelineErr != nil {
+		return pipelineErr
+	}
+
+	fmt.Println(summary)
+	return nil
+}
+
+// runExternalFullSort sorts and spills each batch to its own chunk file,
+// then k-way merges every chunk back into a single ascending-key output.
+func runExternalFullSort(batches <-chan []Record, workers int, path string) (string, error) {
+	var (
+		mu        sync.Mutex
+		tempPaths []string
+	)
+
+	p := newPool(workers)
+	for batch := range batches {
+		batch := batch
+		p.Go(func() error {
+			sort.Slice(batch, func(i, j int) bool { return batch[i].Less(batch[j]) })
+			tmpPath, err := spillRecordsToTempFile(batch)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			tempPaths = append(tempPaths, tmpPath)
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := p.Wait(); err != nil {
+		cleanupTempFiles(tempPaths)
+		return "", err
+	}
+	if len(tempPaths) == 0 {
+		return "", errors.New("input file contains no valid records")
+	}
+
+	outputPath := path + ".sorted"
+	if err := mergeRecordChunkFiles(tempPaths, outputPath); err != nil {
+		cleanupTempFiles(tempPaths)
+		return "", err
+	}
+
+	return fmt.Sprintf("Merged %d chunk(s) from %s into %s", len(tempPaths), path, outputPath), nil
+}
+
+// runExternalTopK reduces every batch to its local topK largest records and
+// folds them into a single shared bounded min-heap, never spilling a chunk
+// file to disk.
+func runExternalTopK(batches <-chan []Record, workers, topK int, path string) (string, error) {
+	var (
+		mu     sync.Mutex
+		global recordHeap
+	)
+
+	p := newPool(workers)
+	for batch := range batches {
+		batch := batch
+		p.Go(func() error {
+			local := recordHeap{}
+			for _, r := range batch {
+				pushTopK(&local, topK, r)
+			}
+
+			mu.Lock()
+			for _, item := range local {
+				pushTopK(&global, topK, item.Record)
+			}
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := p.Wait(); err != nil {
+		return "", err
+	}
+	if len(global) == 0 {
+		return "", errors.New("input file contains no valid records")
+	}
+
+	top := make([]Record, len(global))
+	for i, item := range global {
+		top[i] = item.Record
+	}
+	sort.Slice(top, func(i, j int) bool { return top[j].Less(top[i]) })
+
+	outputPath := fmt.Sprintf("%s.top%d", path, topK)
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	for _, r := range top {
+		fmt.Fprintln(writer, r.Raw)
+	}
+	if err := writer.Flush(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Wrote top %d record(s) from %s into %s", len(top), path, outputPath), nil
+}
+
+// spillToTempFile writes a sorted batch to a new temp file in os.TempDir()
+// and returns its path.
+func spillToTempFile(sorted []int) (string, error) {
+	tmp, err := os.CreateTemp("", "gosort-chunk-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	writer := bufio.NewWriter(tmp)
+	for _, n := range sorted {
+		fmt.Fprintln(writer, n)
+	}
+	if err := writer.Flush(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// sortAndSpillChunks sorts each of chunks concurrently and spills every
+// sorted chunk to its own temp file, returning the temp file paths in the
+// same order as chunks. It's the in-memory counterpart to the batch
+// sort-and-spill loop in runExternalSort, reused by -d mode so its
+// per-file checkpoint manifest can track chunk temp files the same way.
+func sortAndSpillChunks(chunks [][]int, workers int) ([]string, error) {
+	sortedChunks := sortChunksConcurrently(chunks, workers)
+
+	paths := make([]string, len(sortedChunks))
+
+	p := newPool(workers)
+	for i, c := range sortedChunks {
+		i, c := i, c
+		p.Go(func() error {
+			tmpPath, err := spillToTempFile(c)
+			if err != nil {
+				return err
+			}
+			paths[i] = tmpPath
+			return nil
+		})
+	}
+
+	if err := p.Wait(); err != nil {
+		cleanupTempFiles(paths)
+		return nil, err
+	}
+	return paths, nil
+}
+
+func cleanupTempFiles(paths []string) {
+	for _, p := range paths {
+		os.Remove(p)
+	}
+}
+
+// -----------------------------
+// K-way merge via min-heap
+// -----------------------------
+
+// chunkHeapItem is one entry in the k-way merge heap: the current head
+// value read from a chunk's scanner, and the index of that chunk's source.
+type chunkHeapItem struct {
+	value     int
+	sourceIdx int
+}
+
+type chunkHeap []chunkHeapItem
+
+func (h chunkHeap) Len() int            { return len(h) }
+func (h chunkHeap) Less(i, j int) bool  { return h[i].value < h[j].value }
+func (h chunkHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *chunkHeap) Push(x interface{}) { *h = append(*h, x.(chunkHeapItem)) }
+func (h *chunkHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// kWayMergeChunks performs a container/heap-based k-way merge over the
+// already-sorted chunk files at tempPaths, calling emit once per value in
+// ascending order. Every chunk file is closed once it has been fully
+// consumed (or on error); kWayMergeChunks never removes tempPaths itself,
+// since a caller with a checkpoint manifest needs the chunk files to still
+// exist if it crashes before recording the merge as complete.
+func kWayMergeChunks(tempPaths []string, emit func(int) error) error {
+	files := make([]*os.File, len(tempPaths))
+	scanners := make([]*bufio.Scanner, len(tempPaths))
+	for i, p := range tempPaths {
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		files[i] = f
+		scanners[i] = bufio.NewScanner(f)
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	advance := func(h *chunkHeap, sourceIdx int) error {
+		if !scanners[sourceIdx].Scan() {
+			return scanners[sourceIdx].Err()
+		}
+		val, err := strconv.Atoi(strings.TrimSpace(scanners[sourceIdx].Text()))
+		if err != nil {
+			return err
+		}
+		heap.Push(h, chunkHeapItem{value: val, sourceIdx: sourceIdx})
+		return nil
+	}
+
+	h := &chunkHeap{}
+	heap.Init(h)
+	for i := range scanners {
+		if err := advance(h, i); err != nil {
+			return err
+		}
+	}
+
+	for h.Len() > 0 {
+		item := heap.Pop(h).(chunkHeapItem)
+		if err := emit(item.value); err != nil {
+			return err
+		}
+		if err := advance(h, item.sourceIdx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"container/heap"
 	"errors"
 	"flag"
 	"fmt"
@@ -10,10 +11,10 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -27,23 +28,59 @@ func main() {
 	r := flag.Int("r", -1, "generate N random integers (N >= 10)")
 	i := flag.String("i", "", "input file with integers")
 	d := flag.String("d", "", "directory with input .txt files")
+	segmentSize := flag.Int64("segment-size", 512*1024*1024, "max size in bytes of each output segment file in -d mode")
+	x := flag.String("x", "", "input file to sort via external k-way merge (for datasets that don't fit in memory)")
+	chunkLines := flag.Int("chunk-lines", 1_000_000, "number of integers per sorted spill chunk when using -x")
+	var resume bool
+	flag.BoolVar(&resume, "c", false, "resume a previous -d run using its checkpoint manifest")
+	flag.BoolVar(&resume, "resume", false, "alias for -c")
+	w := flag.Int("w", runtime.NumCPU(), "number of concurrent workers for chunk sorting and (-d mode) file processing")
+	keyCol := flag.Int("key-col", 0, "1-indexed CSV/TSV column to sort -x input by; 0 sorts by the whole line")
+	keyType := flag.String("key-type", "int", "sort key type for -x mode: int, float, or time")
+	timeLayout := flag.String("time-layout", time.RFC3339, "time layout used to parse the sort key when --key-type=time")
+	topK := flag.Int("topk", 0, "in -x mode, output only the K largest records instead of a full sort")
+	bench := flag.Bool("bench", false, "run the benchmark harness instead of sorting")
+	benchN := flag.Int("bench-n", 5, "number of --bench iterations")
+	benchSize := flag.Int("bench-size", 100_000, "number of integers generated per --bench iteration")
+	benchSeed := flag.Int64("bench-seed", 1, "seed for --bench's random number generator")
+	benchChunks := flag.Int("bench-chunks", 0, "override the chunk count used per --bench iteration (0 = auto)")
+	benchReadFile := flag.String("bench-read-file", "", "existing sorted output file to probe with random reads instead of running the sort pipeline")
+	benchProbes := flag.Int("bench-probes", 1000, "number of random seek+read probes to perform against --bench-read-file")
+	benchMaxBlock := flag.Int("bench-max-block", 4096, "maximum random read block size in bytes for --bench-read-file probes")
 	flag.Parse()
 
+	maxWorkers := runtime.NumCPU() * 2
+	if *w < 1 || *w > maxWorkers {
+		log.Fatalf("-w must be between 1 and %d (2x NumCPU), got %d", maxWorkers, *w)
+	}
+
 	switch {
+	case *bench:
+		if err := runBench(*benchN, *benchSize, *benchSeed, *benchChunks, *w, *benchReadFile, *benchProbes, *benchMaxBlock); err != nil {
+			log.Fatal(err)
+		}
 	case *r != -1:
-		if err := runRandom(*r); err != nil {
+		if err := runRandom(*r, *w); err != nil {
 			log.Fatal(err)
 		}
 	case *i != "":
-		if err := runInputFile(*i); err != nil {
+		if err := runInputFile(*i, *w); err != nil {
 			log.Fatal(err)
 		}
 	case *d != "":
-		if err := runDirectory(*d); err != nil {
+		if err := runDirectory(*d, *segmentSize, resume, *w); err != nil {
+			log.Fatal(err)
+		}
+	case *x != "":
+		keyFn, err := newKeyFunc(*keyCol, *keyType, *timeLayout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := runExternalSort(*x, *chunkLines, *w, keyFn, *topK); err != nil {
 			log.Fatal(err)
 		}
 	default:
-		log.Fatal("Usage: gosort -r N | -i file.txt | -d directory")
+		log.Fatal("Usage: gosort -r N | -i file.txt | -d directory | -x file.txt | -bench")
 	}
 }
 
@@ -51,7 +88,7 @@ func main() {
 // -r mode
 // -----------------------------
 
-func runRandom(n int) error {
+func runRandom(n, workers int) error {
 	if n < 10 {
 		return errors.New("N must be >= 10")
 	}
@@ -61,7 +98,7 @@ func runRandom(n int) error {
 	fmt.Println("Original numbers:")
 	fmt.Println(numbers)
 
-	processAndPrint(numbers)
+	processAndPrint(numbers, workers)
 	return nil
 }
 
@@ -69,7 +106,7 @@ func runRandom(n int) error {
 // -i mode
 // -----------------------------
 
-func runInputFile(path string) error {
+func runInputFile(path string, workers int) error {
 	numbers, err := readNumbersFromFile(path)
 	if err != nil {
 		return err
@@ -82,7 +119,7 @@ func runInputFile(path string) error {
 	fmt.Println("Original numbers:")
 	fmt.Println(numbers)
 
-	processAndPrint(numbers)
+	processAndPrint(numbers, workers)
 	return nil
 }
 
@@ -90,7 +127,7 @@ func runInputFile(path string) error {
 // -d mode
 // -----------------------------
 
-func runDirectory(dir string) error {
+func runDirectory(dir string, segmentSize int64, resume bool, workers int) error {
 	info, err := os.Stat(dir)
 	if err != nil || !info.IsDir() {
 		return errors.New("invalid directory")
@@ -101,35 +138,101 @@ func runDirectory(dir string) error {
 		return err
 	}
 
+	var mf *manifest
+	if resume {
+		mf, err = loadManifest(outputDir)
+		if err != nil {
+			return err
+		}
+	} else {
+		mf = &manifest{Files: map[string]*manifestFileEntry{}}
+	}
+
 	files, err := os.ReadDir(dir)
 	if err != nil {
 		return err
 	}
 
+	// Files are processed one at a time, each drawing on its own -w-sized
+	// pool for chunk sorting: that -w pool is the only concurrency in -d
+	// mode, so the configured worker count is a hard ceiling on total
+	// concurrent goroutines. Running files concurrently on top of that
+	// would let actual concurrency reach workers^2 (e.g. -w 32 -> up to
+	// 1024 goroutines), which defeats the point of -w as a bound.
+	var errs error
 	for _, f := range files {
 		if filepath.Ext(f.Name()) != ".txt" {
 			continue
 		}
 
-		inputPath := filepath.Join(dir, f.Name())
+		if err := sortDirectoryFile(dir, outputDir, f.Name(), segmentSize, resume, workers, mf); err != nil {
+			errs = errors.Join(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// sortDirectoryFile sorts a single -d mode input file and records its
+// progress in mf, reusing already-spilled chunk temp files from a prior
+// interrupted run when resume is true.
+func sortDirectoryFile(dir, outputDir, name string, segmentSize int64, resume bool, workers int, mf *manifest) error {
+	inputPath := filepath.Join(dir, name)
+	hash, err := hashFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	entry := mf.get(inputPath)
+	if resume && entry != nil && entry.SHA256 == hash && entry.Merged {
+		return nil
+	}
+
+	var chunkPaths []string
+	if resume && entry != nil && entry.SHA256 == hash && !entry.Merged && len(entry.ChunkPaths) == entry.ChunkCount {
+		chunkPaths = entry.ChunkPaths
+	} else {
 		numbers, err := readNumbersFromFile(inputPath)
 		if err != nil {
 			return err
 		}
 		if len(numbers) < 10 {
-			return fmt.Errorf("%s has fewer than 10 numbers", f.Name())
+			return fmt.Errorf("%s has fewer than 10 numbers", name)
 		}
 
 		chunks := splitIntoChunks(numbers)
-		sortedChunks := sortChunksConcurrently(chunks)
-		result := mergeSortedChunks(sortedChunks)
+		chunkPaths, err = sortAndSpillChunks(chunks, workers)
+		if err != nil {
+			return err
+		}
 
-		outputPath := filepath.Join(outputDir, f.Name())
-		if err := writeNumbersToFile(outputPath, result); err != nil {
+		entry = &manifestFileEntry{
+			SourcePath: inputPath,
+			SHA256:     hash,
+			ChunkCount: len(chunkPaths),
+			ChunkPaths: chunkPaths,
+		}
+		mf.set(inputPath, entry)
+		if err := mf.save(outputDir); err != nil {
 			return err
 		}
 	}
 
+	outputPath := filepath.Join(outputDir, name)
+	if err := mergeChunkFilesToSegments(chunkPaths, outputPath, segmentSize); err != nil {
+		return err
+	}
+
+	// Record the merge as complete, and make sure that's durably saved,
+	// before removing the chunk temp files: if we crash between saving
+	// and removing them, a resume just redoes the (idempotent) merge; if
+	// we deleted the chunks first and crashed before saving, a resume
+	// would see Merged == false with no chunk files left to reopen.
+	mf.markMerged(inputPath)
+	if err := mf.save(outputDir); err != nil {
+		return err
+	}
+	cleanupTempFiles(chunkPaths)
 	return nil
 }
 
@@ -137,13 +240,13 @@ func runDirectory(dir string) error {
 // Shared processing
 // -----------------------------
 
-func processAndPrint(numbers []int) {
+func processAndPrint(numbers []int, workers int) {
 	chunks := splitIntoChunks(numbers)
 
 	fmt.Println("\nChunks before sorting:")
 	printChunks(chunks)
 
-	sortedChunks := sortChunksConcurrently(chunks)
+	sortedChunks := sortChunksConcurrently(chunks, workers)
 
 	fmt.Println("\nChunks after sorting:")
 	printChunks(sortedChunks)
@@ -186,18 +289,18 @@ func splitIntoChunks(numbers []int) [][]int {
 // Concurrent sorting
 // -----------------------------
 
-func sortChunksConcurrently(chunks [][]int) [][]int {
-	var wg sync.WaitGroup
-	wg.Add(len(chunks))
+func sortChunksConcurrently(chunks [][]int, workers int) [][]int {
+	p := newPool(workers)
 
 	for i := range chunks {
-		go func(i int) {
-			defer wg.Done()
+		i := i
+		p.Go(func() error {
 			sort.Ints(chunks[i])
-		}(i)
+			return nil
+		})
 	}
 
-	wg.Wait()
+	p.Wait()
 	return chunks
 }
 
@@ -205,31 +308,37 @@ func sortChunksConcurrently(chunks [][]int) [][]int {
 // Merge logic
 // -----------------------------
 
+// mergeSortedChunks k-way merges the already-sorted chunks using a
+// container/heap-based min-heap (the same chunkHeap used to merge spilled
+// chunk files in kWayMergeChunks), rather than scanning every chunk's head
+// on each step.
 func mergeSortedChunks(chunks [][]int) []int {
-	result := []int{}
-
-	indices := make([]int, len(chunks))
-
-	for {
-		minVal := 0
-		minChunk := -1
-
-		for i := range chunks {
-			if indices[i] < len(chunks[i]) {
-				val := chunks[i][indices[i]]
-				if minChunk == -1 || val < minVal {
-					minVal = val
-					minChunk = i
-				}
-			}
-		}
+	total := 0
+	for _, c := range chunks {
+		total += len(c)
+	}
+	result := make([]int, 0, total)
+
+	// next[i] is the index of the next not-yet-pushed element of chunks[i].
+	next := make([]int, len(chunks))
 
-		if minChunk == -1 {
-			break
+	h := &chunkHeap{}
+	heap.Init(h)
+	for i, c := range chunks {
+		if len(c) > 0 {
+			heap.Push(h, chunkHeapItem{value: c[0], sourceIdx: i})
+			next[i] = 1
 		}
+	}
+
+	for h.Len() > 0 {
+		item := heap.Pop(h).(chunkHeapItem)
+		result = append(result, item.value)
 
-		result = append(result, minVal)
-		indices[minChunk]++
+		if idx := next[item.sourceIdx]; idx < len(chunks[item.sourceIdx]) {
+			heap.Push(h, chunkHeapItem{value: chunks[item.sourceIdx][idx], sourceIdx: item.sourceIdx})
+			next[item.sourceIdx] = idx + 1
+		}
 	}
 
 	return result
@@ -272,19 +381,6 @@ func readNumbersFromFile(path string) ([]int, error) {
 	return numbers, scanner.Err()
 }
 
-func writeNumbersToFile(path string, nums []int) error {
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	for _, n := range nums {
-		fmt.Fprintln(file, n)
-	}
-	return nil
-}
-
 func printChunks(chunks [][]int) {
 	for i, c := range chunks {
 		fmt.Printf("Chunk %d: %v\n", i, c)
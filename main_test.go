@@ -0,0 +1,34 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMergeSortedChunksKWayMerge(t *testing.T) {
+	cases := [][][]int{
+		{{1, 4, 7}, {2, 3, 9}, {}, {0, 100}},
+		{{5}},
+		{{}, {}},
+		{{1, 1, 1}, {1, 1}, {0}},
+	}
+
+	for _, chunks := range cases {
+		var want []int
+		for _, c := range chunks {
+			want = append(want, c...)
+		}
+		sort.Ints(want)
+
+		// mergeSortedChunks mutates indices into chunks but not chunks
+		// themselves, so pass a copy of the chunk slice headers.
+		got := mergeSortedChunks(append([][]int(nil), chunks...))
+		if len(want) == 0 {
+			want = []int{}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("mergeSortedChunks(%v) = %v, want %v", chunks, got, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// -----------------------------
+// -c/--resume checkpoint manifest for -d mode
+// -----------------------------
+
+const manifestFileName = ".gosort-manifest.json"
+
+// manifestFileEntry tracks the checkpointed progress for a single input
+// file within a -d run.
+type manifestFileEntry struct {
+	SourcePath string   `json:"source_path"`
+	SHA256     string   `json:"sha256"`
+	ChunkCount int      `json:"chunk_count"`
+	ChunkPaths []string `json:"chunk_paths"`
+	Merged     bool     `json:"merged"`
+}
+
+// manifest is shared across the worker pool that processes -d mode's input
+// files concurrently, so access to Files is guarded by mu.
+type manifest struct {
+	mu    sync.Mutex
+	Files map[string]*manifestFileEntry `json:"files"`
+}
+
+// get returns the manifest entry for path, or nil if there is none yet.
+func (m *manifest) get(path string) *manifestFileEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Files[path]
+}
+
+// set records entry as the manifest entry for path.
+func (m *manifest) set(path string, entry *manifestFileEntry) {
+	m.mu.Lock()
+	m.Files[path] = entry
+	m.mu.Unlock()
+}
+
+// markMerged records that path's chunks have been fully merged into the
+// final output, clearing the chunk paths since the caller is about to
+// remove those temp files. It mutates the entry under mu so it can never
+// race with save's JSON-encoding of the same entry from another worker.
+func (m *manifest) markMerged(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry := m.Files[path]; entry != nil {
+		entry.Merged = true
+		entry.ChunkPaths = nil
+	}
+}
+
+func manifestPath(outputDir string) string {
+	return filepath.Join(outputDir, manifestFileName)
+}
+
+// loadManifest reads the manifest from outputDir, returning an empty
+// manifest if none exists yet.
+func loadManifest(outputDir string) (*manifest, error) {
+	data, err := os.ReadFile(manifestPath(outputDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return &manifest{Files: map[string]*manifestFileEntry{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if m.Files == nil {
+		m.Files = map[string]*manifestFileEntry{}
+	}
+	return &m, nil
+}
+
+// save writes the manifest to outputDir atomically, via a temp file plus
+// rename, so a crash mid-write never leaves a truncated manifest behind.
+// The whole write is serialized by mu since -d mode's worker pool may call
+// save concurrently from multiple files; entry mutations must go through
+// mu as well (see set/markMerged) so marshaling one goroutine's entries
+// never races with another goroutine's plain field writes to its own.
+func (m *manifest) save(outputDir string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	finalPath := manifestPath(outputDir)
+	tmpPath := finalPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, finalPath)
+}
+
+// hashFile returns the hex-encoded SHA-256 digest of the file at path.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
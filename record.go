@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// -----------------------------
+// Pluggable sort-key extraction for -x mode
+// -----------------------------
+
+// Record is one line of input paired with the key it should be sorted by.
+// Raw is kept verbatim so a CSV/TSV row (or any other line format)
+// round-trips unchanged into the sorted output.
+//
+// --key-type=int keeps its key as IntKey (int64) rather than going through
+// FloatKey: float64 only has a 53-bit mantissa, so two distinct large
+// int64s (snowflake IDs, nanosecond timestamps, ...) can round to the same
+// float and sort incorrectly. --key-type=float and --key-type=time use
+// FloatKey, which has enough precision for those domains.
+type Record struct {
+	IntKey   int64
+	FloatKey float64
+	IsInt    bool
+	Raw      string
+}
+
+// Less reports whether r sorts before other. Every record compared in a
+// single run comes from the same KeyFunc, so r.IsInt == other.IsInt always
+// holds.
+func (r Record) Less(other Record) bool {
+	if r.IsInt {
+		return r.IntKey < other.IntKey
+	}
+	return r.FloatKey < other.FloatKey
+}
+
+// KeyFunc extracts the sort key for a line of input, returning a Record
+// with Raw set to line.
+type KeyFunc func(line string) (Record, error)
+
+// newKeyFunc builds a KeyFunc from the --key-col/--key-type/--time-layout
+// flags. keyCol is 1-indexed; 0 means the whole line is the key field.
+func newKeyFunc(keyCol int, keyType, timeLayout string) (KeyFunc, error) {
+	switch keyType {
+	case "int", "float", "time":
+	default:
+		return nil, fmt.Errorf("unknown --key-type %q (want int, float, or time)", keyType)
+	}
+
+	return func(line string) (Record, error) {
+		field := line
+		if keyCol > 0 {
+			f, err := keyColumn(line, keyCol)
+			if err != nil {
+				return Record{}, err
+			}
+			field = f
+		}
+		field = strings.TrimSpace(field)
+
+		switch keyType {
+		case "int":
+			v, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid int key %q: %w", field, err)
+			}
+			return Record{IntKey: v, IsInt: true, Raw: line}, nil
+		case "float":
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid float key %q: %w", field, err)
+			}
+			return Record{FloatKey: v, Raw: line}, nil
+		default: // "time"
+			t, err := time.Parse(timeLayout, field)
+			if err != nil {
+				return Record{}, fmt.Errorf("invalid time key %q: %w", field, err)
+			}
+			return Record{FloatKey: float64(t.UnixNano()), Raw: line}, nil
+		}
+	}, nil
+}
+
+// keyColumn extracts the keyCol'th (1-indexed) field of line, splitting on
+// commas for CSV input or tabs for TSV input.
+func keyColumn(line string, keyCol int) (string, error) {
+	delim := ","
+	if !strings.Contains(line, delim) {
+		delim = "\t"
+	}
+
+	fields := strings.Split(line, delim)
+	if keyCol < 1 || keyCol > len(fields) {
+		return "", fmt.Errorf("line has no column %d: %q", keyCol, line)
+	}
+	return fields[keyCol-1], nil
+}
+
+// -----------------------------
+// Record k-way merge via min-heap
+// -----------------------------
+
+type recordHeapItem struct {
+	Record
+	sourceIdx int
+}
+
+type recordHeap []recordHeapItem
+
+func (h recordHeap) Len() int            { return len(h) }
+func (h recordHeap) Less(i, j int) bool  { return h[i].Record.Less(h[j].Record) }
+func (h recordHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *recordHeap) Push(x interface{}) { *h = append(*h, x.(recordHeapItem)) }
+func (h *recordHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// spillRecordsToTempFile writes a key-sorted batch of records to a new temp
+// file in os.TempDir(), one "key\traw" pair per line, and returns its path.
+// The key is tagged with an "i"/"f" prefix (see formatKey) so
+// parseRecordLine can restore it as the same IntKey/FloatKey
+// representation it started as.
+func spillRecordsToTempFile(sorted []Record) (string, error) {
+	tmp, err := os.CreateTemp("", "gosort-record-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	writer := bufio.NewWriter(tmp)
+	for _, r := range sorted {
+		fmt.Fprintf(writer, "%s\t%s\n", formatKey(r), r.Raw)
+	}
+	if err := writer.Flush(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// formatKey renders r's key as "i<int>" or "f<float>" depending on IsInt,
+// so spillRecordsToTempFile/parseRecordLine round-trip int keys exactly
+// instead of through a lossy float64.
+func formatKey(r Record) string {
+	if r.IsInt {
+		return "i" + strconv.FormatInt(r.IntKey, 10)
+	}
+	return "f" + strconv.FormatFloat(r.FloatKey, 'g', -1, 64)
+}
+
+// mergeRecordChunkFiles merges the already key-sorted chunk files at
+// tempPaths into outputPath in ascending key order, writing each record's
+// Raw line to preserve the original (e.g. CSV/TSV) formatting.
+func mergeRecordChunkFiles(tempPaths []string, outputPath string) error {
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := bufio.NewWriter(out)
+	if err := kWayMergeRecords(tempPaths, func(r Record) error {
+		_, err := fmt.Fprintln(writer, r.Raw)
+		return err
+	}); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// kWayMergeRecords performs a container/heap-based k-way merge over the
+// already key-sorted "key\traw" chunk files at tempPaths, calling emit once
+// per record in ascending key order. Every chunk file is closed and
+// removed once fully consumed (or on error).
+func kWayMergeRecords(tempPaths []string, emit func(Record) error) error {
+	files := make([]*os.File, len(tempPaths))
+	scanners := make([]*bufio.Scanner, len(tempPaths))
+	for i, p := range tempPaths {
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		files[i] = f
+		scanners[i] = bufio.NewScanner(f)
+	}
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+		cleanupTempFiles(tempPaths)
+	}()
+
+	advance := func(h *recordHeap, sourceIdx int) error {
+		if !scanners[sourceIdx].Scan() {
+			return scanners[sourceIdx].Err()
+		}
+		r, err := parseRecordLine(scanners[sourceIdx].Text())
+		if err != nil {
+			return err
+		}
+		heap.Push(h, recordHeapItem{Record: r, sourceIdx: sourceIdx})
+		return nil
+	}
+
+	h := &recordHeap{}
+	heap.Init(h)
+	for i := range scanners {
+		if err := advance(h, i); err != nil {
+			return err
+		}
+	}
+
+	for h.Len() > 0 {
+		item := heap.Pop(h).(recordHeapItem)
+		if err := emit(item.Record); err != nil {
+			return err
+		}
+		if err := advance(h, item.sourceIdx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushTopK maintains h as an ascending min-heap of at most k records, so
+// that once every candidate has been pushed it holds the k largest records
+// seen so far, with the smallest of them at the root.
+func pushTopK(h *recordHeap, k int, r Record) {
+	if k <= 0 {
+		return
+	}
+	if h.Len() < k {
+		heap.Push(h, recordHeapItem{Record: r})
+		return
+	}
+	if (*h)[0].Record.Less(r) {
+		(*h)[0] = recordHeapItem{Record: r}
+		heap.Fix(h, 0)
+	}
+}
+
+// parseRecordLine parses a "key\traw" line written by spillRecordsToTempFile,
+// where key is tagged per formatKey.
+func parseRecordLine(line string) (Record, error) {
+	key, raw, ok := strings.Cut(line, "\t")
+	if !ok || key == "" {
+		return Record{}, fmt.Errorf("malformed spill record: %q", line)
+	}
+
+	kind, value := key[0], key[1:]
+	switch kind {
+	case 'i':
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return Record{}, fmt.Errorf("malformed spill record key %q: %w", key, err)
+		}
+		return Record{IntKey: v, IsInt: true, Raw: raw}, nil
+	case 'f':
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Record{}, fmt.Errorf("malformed spill record key %q: %w", key, err)
+		}
+		return Record{FloatKey: v, Raw: raw}, nil
+	default:
+		return Record{}, fmt.Errorf("malformed spill record key %q: unknown kind %q", key, kind)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+)
+
+func TestNewKeyFuncWholeLine(t *testing.T) {
+	cases := []struct {
+		keyType string
+		line    string
+		wantInt int64
+		isInt   bool
+		wantF   float64
+	}{
+		{"int", "42", 42, true, 0},
+		{"int", "-7", -7, true, 0},
+		{"float", "3.5", 0, false, 3.5},
+	}
+
+	for _, c := range cases {
+		keyFn, err := newKeyFunc(0, c.keyType, time.RFC3339)
+		if err != nil {
+			t.Fatalf("newKeyFunc(%q): %v", c.keyType, err)
+		}
+		rec, err := keyFn(c.line)
+		if err != nil {
+			t.Fatalf("keyFn(%q): %v", c.line, err)
+		}
+		if rec.IsInt != c.isInt {
+			t.Errorf("%q: IsInt = %v, want %v", c.line, rec.IsInt, c.isInt)
+		}
+		if c.isInt && rec.IntKey != c.wantInt {
+			t.Errorf("%q: IntKey = %d, want %d", c.line, rec.IntKey, c.wantInt)
+		}
+		if !c.isInt && rec.FloatKey != c.wantF {
+			t.Errorf("%q: FloatKey = %v, want %v", c.line, rec.FloatKey, c.wantF)
+		}
+		if rec.Raw != c.line {
+			t.Errorf("%q: Raw = %q, want %q", c.line, rec.Raw, c.line)
+		}
+	}
+}
+
+func TestNewKeyFuncIntPreservesFullInt64Precision(t *testing.T) {
+	keyFn, err := newKeyFunc(0, "int", time.RFC3339)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := int64(1) << 60
+	a, err := keyFn("1152921504606846976") // base
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := keyFn("1152921504606846978") // base + 2
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.IntKey != base || b.IntKey != base+2 {
+		t.Fatalf("got IntKey %d, %d, want %d, %d (would collapse through float64)", a.IntKey, b.IntKey, base, base+2)
+	}
+	if !a.Less(b) {
+		t.Fatalf("expected %d < %d", a.IntKey, b.IntKey)
+	}
+}
+
+func TestNewKeyFuncTime(t *testing.T) {
+	keyFn, err := newKeyFunc(0, "time", time.RFC3339)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	earlier, err := keyFn("2020-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	later, err := keyFn("2021-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !earlier.Less(later) {
+		t.Fatalf("expected earlier timestamp to sort before later")
+	}
+}
+
+func TestNewKeyFuncUnknownType(t *testing.T) {
+	if _, err := newKeyFunc(0, "bogus", time.RFC3339); err == nil {
+		t.Fatal("expected an error for an unknown --key-type")
+	}
+}
+
+func TestNewKeyFuncColumnExtraction(t *testing.T) {
+	keyFn, err := newKeyFunc(2, "int", time.RFC3339)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := keyFn("alice,30,engineer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.IntKey != 30 {
+		t.Errorf("IntKey = %d, want 30", rec.IntKey)
+	}
+	if rec.Raw != "alice,30,engineer" {
+		t.Errorf("Raw = %q, want the untouched line", rec.Raw)
+	}
+}
+
+func TestKeyColumnTSVFallback(t *testing.T) {
+	got, err := keyColumn("alice\t30\tengineer", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "30" {
+		t.Errorf("keyColumn = %q, want %q", got, "30")
+	}
+}
+
+func TestKeyColumnOutOfRange(t *testing.T) {
+	if _, err := keyColumn("a,b", 5); err == nil {
+		t.Fatal("expected an error for an out-of-range column")
+	}
+	if _, err := keyColumn("a,b", 0); err == nil {
+		t.Fatal("expected an error for column 0 (keyCol must be >= 1 here)")
+	}
+}
+
+func intRecord(v int64) Record { return Record{IntKey: v, IsInt: true} }
+
+func TestPushTopKZeroIsNoOp(t *testing.T) {
+	h := recordHeap{}
+	pushTopK(&h, 0, intRecord(5))
+	if h.Len() != 0 {
+		t.Fatalf("k=0 should never grow the heap, got len %d", h.Len())
+	}
+}
+
+func TestPushTopKFillsUpToK(t *testing.T) {
+	h := recordHeap{}
+	for _, v := range []int64{3, 1, 2} {
+		pushTopK(&h, 3, intRecord(v))
+	}
+	if h.Len() != 3 {
+		t.Fatalf("expected heap to hold all 3 candidates when k == len, got %d", h.Len())
+	}
+}
+
+func TestPushTopKKeepsOnlyLargestK(t *testing.T) {
+	h := recordHeap{}
+	for _, v := range []int64{5, 1, 9, 2, 7} {
+		pushTopK(&h, 2, intRecord(v))
+	}
+	if h.Len() != 2 {
+		t.Fatalf("expected heap capped at k=2, got len %d", h.Len())
+	}
+
+	got := map[int64]bool{}
+	for h.Len() > 0 {
+		item := heap.Pop(&h).(recordHeapItem)
+		got[item.IntKey] = true
+	}
+	if !got[9] || !got[7] {
+		t.Fatalf("expected the top 2 values {9, 7} to survive, got %v", got)
+	}
+}
+
+func TestPushTopKTieDoesNotEvict(t *testing.T) {
+	h := recordHeap{}
+	pushTopK(&h, 1, intRecord(5))
+	pushTopK(&h, 1, intRecord(5)) // tie: should not replace the existing entry
+	if h.Len() != 1 {
+		t.Fatalf("expected heap to stay at len 1, got %d", h.Len())
+	}
+	if h[0].IntKey != 5 {
+		t.Fatalf("expected the surviving value to remain 5, got %d", h[0].IntKey)
+	}
+}
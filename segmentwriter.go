@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// -----------------------------
+// Segmented output for -d mode
+// -----------------------------
+
+// segmentWriter writes a stream of integers to a series of numbered segment
+// files (name.00000, name.00001, ...), rolling over to the next segment
+// once segmentSize bytes have been written. Each segment is pre-allocated
+// with file.Truncate(segmentSize) up front and truncated back down to its
+// actual written length when it is rolled over or closed.
+type segmentWriter struct {
+	baseName    string
+	segmentSize int64
+
+	segmentIdx int
+	cur        *os.File
+	bufw       *bufio.Writer
+	written    int64
+}
+
+func newSegmentWriter(baseName string, segmentSize int64) (*segmentWriter, error) {
+	if segmentSize < 1 {
+		return nil, fmt.Errorf("segment size must be >= 1 byte, got %d", segmentSize)
+	}
+	return &segmentWriter{baseName: baseName, segmentSize: segmentSize}, nil
+}
+
+// WriteInt appends n to the current segment, rolling over to a new segment
+// first if n wouldn't fit within the size cap.
+func (s *segmentWriter) WriteInt(n int) error {
+	line := strconv.Itoa(n) + "\n"
+
+	switch {
+	case s.cur == nil:
+		if err := s.openSegment(); err != nil {
+			return err
+		}
+	case s.written > 0 && s.written+int64(len(line)) > s.segmentSize:
+		if err := s.rollSegment(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.bufw.WriteString(line); err != nil {
+		return err
+	}
+	s.written += int64(len(line))
+	return nil
+}
+
+// Close flushes and truncates the current segment, if any, down to its
+// actual written length.
+func (s *segmentWriter) Close() error {
+	return s.closeCurrent()
+}
+
+func (s *segmentWriter) openSegment() error {
+	name := fmt.Sprintf("%s.%05d", s.baseName, s.segmentIdx)
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(s.segmentSize); err != nil {
+		f.Close()
+		return err
+	}
+
+	s.cur = f
+	s.bufw = bufio.NewWriter(f)
+	s.written = 0
+	return nil
+}
+
+func (s *segmentWriter) rollSegment() error {
+	if err := s.closeCurrent(); err != nil {
+		return err
+	}
+	s.segmentIdx++
+	return s.openSegment()
+}
+
+func (s *segmentWriter) closeCurrent() error {
+	if s.cur == nil {
+		return nil
+	}
+	if err := s.bufw.Flush(); err != nil {
+		s.cur.Close()
+		return err
+	}
+	if err := s.cur.Truncate(s.written); err != nil {
+		s.cur.Close()
+		return err
+	}
+	err := s.cur.Close()
+	s.cur = nil
+	s.bufw = nil
+	return err
+}
+
+// mergeChunkFilesToSegments k-way merges the already-sorted chunk files at
+// tempPaths and writes the result through a segmentWriter rooted at
+// baseName, so the merged output is split across size-capped segment files
+// the same way a plain (non-resumed) -d run would be.
+func mergeChunkFilesToSegments(tempPaths []string, baseName string, segmentSize int64) error {
+	sw, err := newSegmentWriter(baseName, segmentSize)
+	if err != nil {
+		return err
+	}
+
+	if err := kWayMergeChunks(tempPaths, sw.WriteInt); err != nil {
+		sw.Close()
+		return err
+	}
+
+	return sw.Close()
+}
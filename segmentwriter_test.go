@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestSegmentWriterRollsOverAndTruncates(t *testing.T) {
+	dir := t.TempDir()
+	base := dir + "/out"
+
+	// "12\n" is 3 bytes; cap each segment at 7 bytes so it rolls over
+	// after two values.
+	sw, err := newSegmentWriter(base, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []int{12, 34, 56, 78, 9}
+	for _, v := range values {
+		if err := sw.WriteInt(v); err != nil {
+			t.Fatalf("WriteInt(%d): %v", v, err)
+		}
+	}
+	if err := sw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var got []int
+	for segIdx := 0; ; segIdx++ {
+		name := fmt.Sprintf("%s.%05d", base, segIdx)
+		data, err := os.ReadFile(name)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadFile(%s): %v", name, err)
+		}
+
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(info.Size()) != len(data) {
+			t.Errorf("segment %s not truncated to its written length: size %d, content %d bytes", name, info.Size(), len(data))
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			n, err := strconv.Atoi(scanner.Text())
+			if err != nil {
+				t.Fatalf("parsing %s: %v", name, err)
+			}
+			got = append(got, n)
+		}
+	}
+
+	if len(got) != len(values) {
+		t.Fatalf("got %v across segments, want %v", got, values)
+	}
+	for i, v := range values {
+		if got[i] != v {
+			t.Errorf("value %d = %d, want %d", i, got[i], v)
+		}
+	}
+}
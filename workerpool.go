@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// -----------------------------
+// -w bounded worker pool
+// -----------------------------
+
+// pool bounds concurrency to a fixed number of in-flight goroutines via a
+// buffered channel semaphore, and aggregates errors from the work it runs
+// the way an errgroup would: the first failing task cancels pool's context
+// so queued-but-not-yet-started work can bail out early, and every error is
+// collected with errors.Join rather than just keeping the first one.
+type pool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newPool(workers int) *pool {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &pool{sem: make(chan struct{}, workers), ctx: ctx, cancel: cancel}
+}
+
+// Go runs fn in a goroutine once a slot is free. If an earlier task already
+// failed, fn still acquires and releases a slot but is not invoked.
+func (p *pool) Go(fn func() error) {
+	p.wg.Add(1)
+	p.sem <- struct{}{}
+
+	go func() {
+		defer p.wg.Done()
+		defer func() { <-p.sem }()
+
+		if p.ctx.Err() != nil {
+			return
+		}
+		if err := fn(); err != nil {
+			p.mu.Lock()
+			p.err = errors.Join(p.err, err)
+			p.mu.Unlock()
+			p.cancel()
+		}
+	}()
+}
+
+// Wait blocks until every submitted task has returned and reports the
+// joined error from any that failed, or nil if none did.
+func (p *pool) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+	return p.err
+}